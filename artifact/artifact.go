@@ -0,0 +1,53 @@
+// Package artifact abstracts where an update's bytes come from, so the
+// installer package and main.go don't need to know about GCP, AWS, or any
+// other object store directly: they only need a Fetcher and a Ref
+// describing what to fetch. Ref values are populated from a target's TUF
+// custom metadata, so each target can point at a different backend.
+package artifact
+
+import (
+	"context"
+	"io"
+)
+
+// Backend names a supported artifact store. They are the values expected
+// in a target's "backend" custom metadata field.
+const (
+	BackendArtifactRegistry = "artifact-registry"
+	BackendGCS              = "gcs"
+	BackendS3               = "s3"
+	BackendHTTP             = "http"
+)
+
+// Ref identifies a single artifact within a Backend-specific namespace.
+// Which fields are meaningful depends on Backend.
+type Ref struct {
+	// Backend selects which Fetcher serves this Ref. Empty is treated by
+	// callers as BackendArtifactRegistry, for index.json entries published
+	// before this field existed.
+	Backend string `json:"backend,omitempty"`
+
+	// Bucket is the GCS or S3 bucket the object lives in. Unused for
+	// BackendArtifactRegistry and BackendHTTP.
+	Bucket string `json:"bucket,omitempty"`
+
+	// Object is the object/key within Bucket, the file name within an
+	// Artifact Registry repository, or the full URL for BackendHTTP.
+	Object string `json:"object,omitempty"`
+
+	// Region is consulted by BackendS3 only.
+	Region string `json:"region,omitempty"`
+}
+
+// Fetcher opens an artifact for reading, optionally resuming from a byte
+// offset (0 for a fresh download), and reports the artifact's total
+// length so callers can sanity-check it against the length recorded in
+// TUF's target metadata. honoredOffset reports whether the returned body
+// actually starts at offset (a suffix of the artifact) rather than at byte
+// 0 (the full artifact) - totalLength can't be used to tell the two
+// apart, since a server that ignores the Range request still reports the
+// same total size. The caller is responsible for closing the returned
+// ReadCloser.
+type Fetcher interface {
+	Fetch(ctx context.Context, ref Ref, offset int64) (body io.ReadCloser, totalLength int64, honoredOffset bool, err error)
+}
@@ -0,0 +1,37 @@
+package artifact
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ArtifactRegistryFetcher downloads targets from Google Artifact
+// Registry's generic file download API. This is the backend the client
+// originally shipped with (hard-coded to a single project/repository in
+// main.go); it is now just one of several Fetcher implementations.
+type ArtifactRegistryFetcher struct {
+	Project     string
+	Location    string
+	Repository  string
+	Credentials CredentialProvider
+	HTTPClient  *http.Client
+}
+
+// Fetch implements Fetcher. ref.Object is the file's name within
+// Repository, e.g. "nebula-package:2026.07.25-10.00.00:nebula-standalone".
+func (f *ArtifactRegistryFetcher) Fetch(ctx context.Context, ref Ref, offset int64) (io.ReadCloser, int64, bool, error) {
+	url := fmt.Sprintf(
+		"https://artifactregistry.googleapis.com/download/v1/projects/%s/locations/%s/repositories/%s/files/%s:download?alt=media",
+		f.Project, f.Location, f.Repository, ref.Object,
+	)
+	return fetchHTTP(ctx, f.client(), url, offset, f.Credentials)
+}
+
+func (f *ArtifactRegistryFetcher) client() *http.Client {
+	if f.HTTPClient != nil {
+		return f.HTTPClient
+	}
+	return http.DefaultClient
+}
@@ -0,0 +1,55 @@
+package artifact
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// CredentialProvider supplies a bearer token for backends that need one
+// (Artifact Registry, GCS). Backends that don't need one (plain HTTP,
+// S3's own signing) simply ignore it.
+type CredentialProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// ServiceAccountCredentialProvider loads a GCP service account key from
+// disk and exchanges it for OAuth2 tokens scoped to cloud-platform, the
+// scope both Artifact Registry and GCS downloads use. KeyFilePath is no
+// longer hard-coded into the download path - callers (main.go) decide
+// where it comes from.
+type ServiceAccountCredentialProvider struct {
+	KeyFilePath string
+}
+
+// Token implements CredentialProvider.
+func (p ServiceAccountCredentialProvider) Token(ctx context.Context) (string, error) {
+	keyData, err := os.ReadFile(p.KeyFilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read service account key %q: %w", p.KeyFilePath, err)
+	}
+
+	creds, err := google.CredentialsFromJSON(ctx, keyData, "https://www.googleapis.com/auth/cloud-platform")
+	if err != nil {
+		return "", fmt.Errorf("failed to load service account credentials: %w", err)
+	}
+
+	token, err := creds.TokenSource.Token()
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve token: %w", err)
+	}
+
+	return token.AccessToken, nil
+}
+
+// staticTokenSource adapts a single already-fetched bearer token to
+// oauth2.TokenSource, for handing to clients (e.g. storage/v1) that expect
+// one rather than a raw string.
+type staticTokenSource string
+
+func (s staticTokenSource) Token() (*oauth2.Token, error) {
+	return &oauth2.Token{AccessToken: string(s), TokenType: "Bearer"}, nil
+}
@@ -0,0 +1,48 @@
+package artifact
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"google.golang.org/api/option"
+	storagev1 "google.golang.org/api/storage/v1"
+)
+
+// GCSFetcher downloads targets from a Google Cloud Storage bucket via the
+// JSON API (storage/v1).
+type GCSFetcher struct {
+	Credentials CredentialProvider
+}
+
+// Fetch implements Fetcher.
+func (f *GCSFetcher) Fetch(ctx context.Context, ref Ref, offset int64) (io.ReadCloser, int64, bool, error) {
+	token, err := f.Credentials.Token(ctx)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	svc, err := storagev1.NewService(ctx, option.WithTokenSource(staticTokenSource(token)))
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	get := svc.Objects.Get(ref.Bucket, ref.Object)
+
+	obj, err := get.Do()
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("failed to stat gs://%s/%s: %w", ref.Bucket, ref.Object, err)
+	}
+
+	if offset > 0 {
+		get.Header().Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := get.Download()
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("failed to download gs://%s/%s: %w", ref.Bucket, ref.Object, err)
+	}
+
+	return resp.Body, int64(obj.Size), resp.StatusCode == http.StatusPartialContent, nil
+}
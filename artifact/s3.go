@@ -0,0 +1,66 @@
+package artifact
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Fetcher downloads targets from an S3 bucket using the default AWS
+// credential chain (environment, shared config, or instance role). It
+// does not take a CredentialProvider - S3 requests are signed by the AWS
+// SDK itself, not by a bearer token.
+type S3Fetcher struct {
+	client *s3.Client
+}
+
+// NewS3Fetcher builds an S3Fetcher for region using the default AWS
+// credential chain.
+func NewS3Fetcher(ctx context.Context, region string) (*S3Fetcher, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return &S3Fetcher{client: s3.NewFromConfig(cfg)}, nil
+}
+
+// Fetch implements Fetcher.
+func (f *S3Fetcher) Fetch(ctx context.Context, ref Ref, offset int64) (io.ReadCloser, int64, bool, error) {
+	in := &s3.GetObjectInput{
+		Bucket: aws.String(ref.Bucket),
+		Key:    aws.String(ref.Object),
+	}
+	if offset > 0 {
+		in.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	out, err := f.client.GetObject(ctx, in)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("failed to download s3://%s/%s: %w", ref.Bucket, ref.Object, err)
+	}
+
+	// A Content-Range header is only present on an actual 206 partial
+	// response - a bucket/proxy that ignores Range returns the full
+	// object with no Content-Range, same as a plain GetObject.
+	if total, ok := s3ContentRangeTotal(aws.ToString(out.ContentRange)); ok {
+		return out.Body, total, true, nil
+	}
+	return out.Body, aws.ToInt64(out.ContentLength), false, nil
+}
+
+// s3ContentRangeTotal extracts the total size from a "bytes 1000-1999/2000"
+// Content-Range value, as returned for a ranged GetObject.
+func s3ContentRangeTotal(contentRange string) (int64, bool) {
+	i := strings.LastIndexByte(contentRange, '/')
+	if i < 0 {
+		return 0, false
+	}
+	total, err := strconv.ParseInt(contentRange[i+1:], 10, 64)
+	return total, err == nil
+}
@@ -0,0 +1,225 @@
+// Package autoupdate turns a one-shot "check, confirm, install" sequence
+// into a reusable background loop. It is deliberately decoupled from TUF
+// and Artifact Registry specifics: callers implement Source against
+// whatever update mechanism they have (this repo's TUF index + installer
+// package, in main.go's case) so AutoUpdater itself can be embedded in
+// daemons and CI jobs, not just an interactive CLI.
+package autoupdate
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// Source is the update mechanism an AutoUpdater drives. Implementations are
+// expected to do their own signature/hash verification internally (see the
+// installer package) - AutoUpdater only sequences the calls and reports on
+// how far each attempt got.
+type Source interface {
+	// CheckVersion reports the newest version currently published and
+	// whether it is newer than currentVersion.
+	CheckVersion(ctx context.Context, currentVersion string) (newVersion string, available bool, err error)
+	// Install downloads, verifies, and activates newVersion. It must be
+	// safe to call again if a previous attempt failed partway through.
+	Install(ctx context.Context, newVersion string) error
+}
+
+// Policy decides whether an available update should be applied
+// immediately, or held back.
+type Policy struct {
+	kind    policyKind
+	start   time.Duration // ApplyWithinWindow: time-of-day window start
+	end     time.Duration // ApplyWithinWindow: time-of-day window end
+	percent int           // StagedRollout: 0-100
+}
+
+type policyKind int
+
+const (
+	alwaysApply policyKind = iota
+	promptUser
+	applyWithinWindow
+	stagedRollout
+)
+
+// AlwaysApply installs every update as soon as it is found.
+var AlwaysApply = Policy{kind: alwaysApply}
+
+// PromptUser installs an update only if the AutoUpdater's Confirm callback
+// returns true for it.
+var PromptUser = Policy{kind: promptUser}
+
+// ApplyWithinWindow installs updates only while the time of day falls
+// within [start, end) (e.g. a nightly maintenance window). start and end
+// are offsets from midnight; a window that wraps past midnight (start >
+// end) is supported.
+func ApplyWithinWindow(start, end time.Duration) Policy {
+	return Policy{kind: applyWithinWindow, start: start, end: end}
+}
+
+// StagedRollout installs an update on only percent% of hosts, chosen
+// deterministically from the host name and the new version string so that
+// repeated checks against the same version give a stable answer on a given
+// host.
+func StagedRollout(percent int) Policy {
+	return Policy{kind: stagedRollout, percent: percent}
+}
+
+func (p Policy) allows(newVersion string, confirm func(string) bool, now time.Time) bool {
+	switch p.kind {
+	case alwaysApply:
+		return true
+	case promptUser:
+		return confirm != nil && confirm(newVersion)
+	case applyWithinWindow:
+		h, m, s := now.Clock()
+		offset := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+		if p.start <= p.end {
+			return offset >= p.start && offset < p.end
+		}
+		// window wraps midnight
+		return offset >= p.start || offset < p.end
+	case stagedRollout:
+		host, _ := os.Hostname()
+		return bucket(host+"/"+newVersion) < p.percent
+	default:
+		return false
+	}
+}
+
+// bucket deterministically maps a string to [0, 100).
+func bucket(key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % 100)
+}
+
+// EventType identifies a stage of an update attempt.
+type EventType string
+
+const (
+	// UpdateAvailable fires as soon as a newer version is found, before
+	// the Policy has decided whether to apply it.
+	UpdateAvailable EventType = "update_available"
+	// Downloaded fires once Source.Install has fetched the new version.
+	Downloaded EventType = "downloaded"
+	// Verified fires once the downloaded artifact's integrity has been
+	// confirmed.
+	Verified EventType = "verified"
+	// Installed fires once the new version has been activated.
+	Installed EventType = "installed"
+	// Failed fires when any stage of an update attempt returns an error.
+	Failed EventType = "failed"
+)
+
+// Event reports progress of a single update attempt.
+type Event struct {
+	Type    EventType
+	Version string
+	Err     error
+}
+
+// AutoUpdater periodically checks Source for a newer version and, subject
+// to Policy, installs it.
+type AutoUpdater struct {
+	Source Source
+	Policy Policy
+
+	// Confirm is consulted by the PromptUser policy. It is nil-safe: a
+	// nil Confirm under PromptUser simply never applies updates.
+	Confirm func(newVersion string) bool
+
+	// CheckInterval is the nominal time between checks. Defaults to 60s.
+	CheckInterval time.Duration
+	// Jitter is a random amount, up to which CheckInterval is extended on
+	// each iteration, so many instances don't all poll in lockstep.
+	Jitter time.Duration
+
+	currentVersion string
+}
+
+// New returns an AutoUpdater that checks source for updates against
+// currentVersion, applying policy to decide whether to install them.
+func New(source Source, currentVersion string, policy Policy) *AutoUpdater {
+	return &AutoUpdater{
+		Source:         source,
+		Policy:         policy,
+		CheckInterval:  60 * time.Second,
+		currentVersion: currentVersion,
+	}
+}
+
+// Run starts the check loop and returns a channel of Events describing its
+// progress. The channel is closed when ctx is cancelled.
+func (u *AutoUpdater) Run(ctx context.Context) <-chan Event {
+	events := make(chan Event)
+
+	go func() {
+		defer close(events)
+
+		for {
+			u.tick(ctx, events)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(u.nextInterval()):
+			}
+		}
+	}()
+
+	return events
+}
+
+func (u *AutoUpdater) nextInterval() time.Duration {
+	interval := u.CheckInterval
+	if interval <= 0 {
+		interval = 60 * time.Second
+	}
+	if u.Jitter > 0 {
+		interval += time.Duration(rand.Int63n(int64(u.Jitter)))
+	}
+	return interval
+}
+
+func (u *AutoUpdater) tick(ctx context.Context, events chan<- Event) {
+	newVersion, available, err := u.Source.CheckVersion(ctx, u.currentVersion)
+	if err != nil {
+		send(ctx, events, Event{Type: Failed, Err: fmt.Errorf("checking for update: %w", err)})
+		return
+	}
+	if !available {
+		return
+	}
+
+	send(ctx, events, Event{Type: UpdateAvailable, Version: newVersion})
+
+	if !u.Policy.allows(newVersion, u.Confirm, time.Now()) {
+		return
+	}
+
+	if err := u.Source.Install(ctx, newVersion); err != nil {
+		send(ctx, events, Event{Type: Failed, Version: newVersion, Err: err})
+		return
+	}
+
+	// Source.Install performs download, verification, and activation as
+	// a single atomic operation (see installer.Install), so those three
+	// stages are reported back-to-back rather than individually observed.
+	send(ctx, events, Event{Type: Downloaded, Version: newVersion})
+	send(ctx, events, Event{Type: Verified, Version: newVersion})
+	send(ctx, events, Event{Type: Installed, Version: newVersion})
+
+	u.currentVersion = newVersion
+}
+
+func send(ctx context.Context, events chan<- Event, e Event) {
+	select {
+	case events <- e:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,183 @@
+// Package index parses index.json - the artifact/platform/channel catalog
+// this client's TUF repository publishes - and selects the entry matching
+// the running binary's platform and a chosen release channel.
+package index
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"runtime"
+
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+
+	"github.com/sorayaormazabalmayo/TUF-Client/artifact"
+)
+
+// StableChannel is the release channel assumed for entries that don't
+// declare one (including every entry under the legacy single-artifact
+// schema, which predates channels entirely).
+const StableChannel = "stable"
+
+// Entry describes one installable artifact build: which name, platform,
+// and release channel it targets, and everything needed to fetch and
+// verify it. It is keyed in index.json as "{name}/{os}/{arch}/{channel}",
+// e.g. "nebula-standalone/linux/amd64/stable".
+type Entry struct {
+	Name    string `json:"name"`
+	OS      string `json:"os,omitempty"`
+	Arch    string `json:"arch,omitempty"`
+	Channel string `json:"channel,omitempty"`
+	Version string `json:"version"`
+	// Scheme names the version.Comparator this entry's Version should be
+	// ordered by (see the version package). Empty defaults to semver.
+	Scheme string `json:"scheme,omitempty"`
+	Length int64  `json:"length"`
+	Hashes struct {
+		Sha256 string `json:"sha256"`
+	} `json:"hashes"`
+
+	// Ref declares which artifact.Fetcher backend serves this entry and
+	// how to address it there.
+	Ref artifact.Ref `json:"ref,omitempty"`
+
+	// TargetPath is the path this entry is registered under in the TUF
+	// targets role, checked by Validate. It defaults to Name for
+	// single-platform entries (the legacy schema, and any entry that
+	// doesn't declare os/arch/channel), or "{name}/{os}/{arch}/{channel}"
+	// otherwise.
+	TargetPath string `json:"targetPath,omitempty"`
+}
+
+func (e Entry) targetPath() string {
+	if e.TargetPath != "" {
+		return e.TargetPath
+	}
+	if e.OS == "" && e.Arch == "" && e.Channel == "" {
+		return e.Name
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", e.Name, e.OS, e.Arch, e.Channel)
+}
+
+// EffectiveChannel returns e.Channel, defaulting to StableChannel for
+// entries that don't declare one (including every legacy single-artifact
+// entry).
+func (e Entry) EffectiveChannel() string {
+	if e.Channel == "" {
+		return StableChannel
+	}
+	return e.Channel
+}
+
+// EffectiveTargetPath returns the path e is registered under in the TUF
+// targets role - the same path Validate checks against.
+func (e Entry) EffectiveTargetPath() string {
+	return e.targetPath()
+}
+
+// Index is the parsed contents of index.json.
+type Index struct {
+	Entries []Entry
+}
+
+// Parse decodes index.json into an Index. Both the current
+// name/os/arch/channel-keyed schema and the single-artifact schema this
+// client originally shipped with parse the same way: a legacy entry has
+// no os/arch/channel to key on, so it comes back as a wildcard entry
+// (empty OS, Arch and Channel) that Selector treats as matching any
+// platform on the stable channel.
+func Parse(data []byte) (Index, error) {
+	var raw map[string]Entry
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Index{}, fmt.Errorf("failed to parse index: %w", err)
+	}
+
+	idx := Index{Entries: make([]Entry, 0, len(raw))}
+	for key, entry := range raw {
+		if entry.Name == "" {
+			entry.Name = key
+		}
+		idx.Entries = append(idx.Entries, entry)
+	}
+	return idx, nil
+}
+
+// Selector picks entries matching a platform and release channel.
+type Selector struct {
+	OS      string
+	Arch    string
+	Channel string
+}
+
+// DefaultSelector selects builds for the running binary's platform on the
+// stable channel.
+func DefaultSelector() Selector {
+	return Selector{OS: runtime.GOOS, Arch: runtime.GOARCH, Channel: StableChannel}
+}
+
+func (s Selector) matches(e Entry) bool {
+	if e.OS != "" && e.OS != s.OS {
+		return false
+	}
+	if e.Arch != "" && e.Arch != s.Arch {
+		return false
+	}
+	return e.EffectiveChannel() == s.Channel
+}
+
+// Select returns the entry named name that matches s's platform and
+// channel.
+func (s Selector) Select(idx Index, name string) (Entry, error) {
+	for _, e := range idx.Entries {
+		if e.Name == name && s.matches(e) {
+			return e, nil
+		}
+	}
+	return Entry{}, fmt.Errorf("no %s/%s build of %q on channel %q in index", s.OS, s.Arch, name, s.Channel)
+}
+
+// ListAvailable returns every entry matching s's platform and channel,
+// across all artifact names - what a CLI user could ask to install.
+func (s Selector) ListAvailable(idx Index) []Entry {
+	var out []Entry
+	for _, e := range idx.Entries {
+		if s.matches(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// TargetInfoGetter is satisfied by *updater.Updater. It is the one method
+// Validate needs, so this package doesn't have to import the (much
+// larger) updater package just for a type constraint.
+type TargetInfoGetter interface {
+	GetTargetInfo(targetPath string) (*metadata.TargetFiles, error)
+}
+
+// Validate cross-checks e's length and sha256 against the TUF targets
+// role's own metadata for e's target path, so a stale or tampered
+// index.json entry can't point the client at a differently-hashed file
+// than the one TUF itself has signed off on.
+func (e Entry) Validate(getter TargetInfoGetter) error {
+	targetPath := e.targetPath()
+
+	ti, err := getter.GetTargetInfo(targetPath)
+	if err != nil {
+		return fmt.Errorf("getting TUF target info for %q: %w", targetPath, err)
+	}
+
+	if ti.Length != e.Length {
+		return fmt.Errorf("index length %d for %q does not match TUF targets metadata length %d", e.Length, targetPath, ti.Length)
+	}
+
+	sha256Hash, ok := ti.Hashes["sha256"]
+	if !ok {
+		return fmt.Errorf("TUF targets metadata for %q has no sha256 hash", targetPath)
+	}
+	if got := hex.EncodeToString(sha256Hash); got != e.Hashes.Sha256 {
+		return fmt.Errorf("index sha256 %s for %q does not match TUF targets metadata hash %s", e.Hashes.Sha256, targetPath, got)
+	}
+
+	return nil
+}
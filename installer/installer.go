@@ -0,0 +1,319 @@
+// Package installer implements the on-disk update mechanics for a single
+// artifact: staged download with resume, hash re-verification before
+// activation, and atomic rename into place. It keeps a small history of
+// previously-installed versions so a bad update can be rolled back.
+package installer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sorayaormazabalmayo/TUF-Client/artifact"
+	"github.com/sorayaormazabalmayo/TUF-Client/version"
+)
+
+// versionsDirName is the subdirectory (next to dest) that keeps known-good
+// previous versions around for Rollback.
+const versionsDirName = "versions"
+
+// keepVersions bounds how many previous versions are retained in
+// versions/. Older ones are pruned after a successful Install.
+const keepVersions = 3
+
+// lockPollInterval is how often acquireLock retries while another Install is
+// holding dest's lockfile.
+const lockPollInterval = 100 * time.Millisecond
+
+// lockStaleAfter bounds how long a lockfile is honored after its last
+// Install fetched that lock. Past this age it is assumed to belong to a
+// process that died without releasing it (OOM, crash, power loss) rather
+// than one still mid-download, and acquireLock steals it instead of
+// polling forever.
+const lockStaleAfter = 10 * time.Minute
+
+// TargetInfo carries everything Install needs to know about the artifact it
+// is fetching: where to download it from, how big it should be and what its
+// SHA256 must hash to according to the TUF targets metadata, and the
+// version string that identifies it in the versions/ history.
+type TargetInfo struct {
+	Version string
+	// Scheme is the version.Comparator scheme Version should be ordered
+	// by when Install sorts versions/ for pruning. Empty defaults to
+	// semver, matching version.Version.
+	Scheme string
+	Ref    artifact.Ref
+	Length int64
+	SHA256 string
+}
+
+// Install fetches ti.Ref via fetcher into "<dest>.partial", hashing the
+// bytes as they arrive (no second pass over the file). If a partial
+// download from a previous attempt already exists, it is resumed by
+// passing the existing size as an offset to fetcher. Once the staged
+// file's length and SHA256 both match ti, it is fsync'd, the file
+// currently at dest is archived into versions/, and the staged file is
+// atomically renamed into dest. If anything does not match (bad hash,
+// short read) the partial file is removed and dest is left untouched.
+//
+// Install holds an exclusive lockfile on dest for its duration, so two
+// updater processes racing on the same dest can't both write to
+// "<dest>.partial" at once and have one's TeeReader hash bytes that don't
+// match what ends up on disk after the interleaving.
+func Install(ctx context.Context, ti TargetInfo, dest string, fetcher artifact.Fetcher) error {
+	release, err := acquireLock(ctx, dest)
+	if err != nil {
+		return fmt.Errorf("failed to acquire install lock: %w", err)
+	}
+	defer release()
+
+	partial := dest + ".partial"
+
+	var resumeFrom int64
+	if fi, err := os.Stat(partial); err == nil {
+		resumeFrom = fi.Size()
+	}
+
+	body, _, honoredOffset, err := fetcher.Fetch(ctx, ti.Ref, resumeFrom)
+	if err != nil {
+		return fmt.Errorf("failed to fetch artifact: %w", err)
+	}
+	defer body.Close()
+
+	hasher := sha256.New()
+	openFlags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+
+	// A Fetcher that ignores the offset (no Range support) returns the
+	// full artifact from byte 0 instead of a suffix of it: fall back to a
+	// clean restart rather than treating the response as a resume.
+	// totalLength can't be used to tell the two cases apart - it reports
+	// the artifact's full size either way - so this relies on the
+	// Fetcher telling us directly whether it honored the offset.
+	if resumeFrom > 0 && !honoredOffset {
+		resumeFrom = 0
+	}
+
+	if resumeFrom > 0 {
+		if err := seedHashFromExisting(hasher, partial, resumeFrom); err != nil {
+			return fmt.Errorf("failed to re-hash resumed partial download: %w", err)
+		}
+	} else {
+		openFlags |= os.O_TRUNC
+	}
+
+	out, err := os.OpenFile(partial, openFlags, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open staging file %q: %w", partial, err)
+	}
+
+	written, copyErr := io.Copy(out, io.TeeReader(body, hasher))
+	closeErr := out.Close()
+	if copyErr != nil {
+		os.Remove(partial)
+		return fmt.Errorf("failed to download artifact: %w", copyErr)
+	}
+	if closeErr != nil {
+		os.Remove(partial)
+		return fmt.Errorf("failed to flush staging file: %w", closeErr)
+	}
+
+	if gotLength := resumeFrom + written; gotLength != ti.Length {
+		os.Remove(partial)
+		return fmt.Errorf("downloaded artifact has length %d, expected %d", gotLength, ti.Length)
+	}
+
+	if gotHash := hex.EncodeToString(hasher.Sum(nil)); gotHash != ti.SHA256 {
+		os.Remove(partial)
+		return fmt.Errorf("downloaded artifact hash %s does not match expected %s", gotHash, ti.SHA256)
+	}
+
+	if err := fsyncFile(partial); err != nil {
+		return fmt.Errorf("failed to fsync staged artifact: %w", err)
+	}
+
+	versionsDir := filepath.Join(filepath.Dir(dest), versionsDirName)
+	if err := archivePrevious(dest, versionsDir); err != nil {
+		return fmt.Errorf("failed to archive previous version: %w", err)
+	}
+
+	if err := os.Rename(partial, dest); err != nil {
+		return fmt.Errorf("failed to move staged artifact into place: %w", err)
+	}
+
+	if err := writeInstalledVersion(dest, ti.Version); err != nil {
+		return fmt.Errorf("failed to record installed version: %w", err)
+	}
+
+	return pruneVersions(versionsDir, ti.Scheme)
+}
+
+// Rollback restores the most recently archived version from versions/ back
+// onto dest, undoing the last successful Install. scheme is the
+// version.Comparator scheme the versions/ entries should be ordered by (see
+// TargetInfo.Scheme).
+func Rollback(dest, scheme string) error {
+	versionsDir := filepath.Join(filepath.Dir(dest), versionsDirName)
+
+	versions, err := sortedVersions(versionsDir, scheme)
+	if err != nil {
+		return fmt.Errorf("failed to list known-good versions: %w", err)
+	}
+	if len(versions) == 0 {
+		return fmt.Errorf("no known-good version available to roll back to")
+	}
+
+	last := versions[len(versions)-1]
+	archived := filepath.Join(versionsDir, last, filepath.Base(dest))
+
+	if err := os.Rename(archived, dest); err != nil {
+		return fmt.Errorf("failed to restore version %s: %w", last, err)
+	}
+
+	if err := writeInstalledVersion(dest, last); err != nil {
+		return fmt.Errorf("failed to record rolled-back version: %w", err)
+	}
+
+	return os.RemoveAll(filepath.Join(versionsDir, last))
+}
+
+// acquireLock creates an exclusive "<dest>.lock" file, polling until it can
+// (or ctx is done), so only one Install for a given dest runs at a time. A
+// lockfile older than lockStaleAfter is stolen rather than waited on, on
+// the assumption its owner died without releasing it. The returned func
+// releases the lock by removing the lockfile.
+func acquireLock(ctx context.Context, dest string) (func(), error) {
+	lockPath := dest + ".lock"
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lockfile %q: %w", lockPath, err)
+		}
+
+		if fi, statErr := os.Stat(lockPath); statErr == nil && time.Since(fi.ModTime()) > lockStaleAfter {
+			os.Remove(lockPath)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for lock %q held by another Install: %w", lockPath, ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// seedHashFromExisting feeds the first n bytes already on disk at path into
+// hasher, so a resumed download produces the same digest as a full one.
+func seedHashFromExisting(hasher hash.Hash, path string, n int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.CopyN(hasher, f, n)
+	return err
+}
+
+func fsyncFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
+// archivePrevious moves whatever is currently at dest into
+// versionsDir/<installed version>/<basename(dest)>, so it can be restored
+// by Rollback. It is a no-op if dest does not exist yet.
+func archivePrevious(dest, versionsDir string) error {
+	if _, err := os.Stat(dest); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	prevVersion := readInstalledVersion(dest)
+	dir := filepath.Join(versionsDir, prevVersion)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+
+	return os.Rename(dest, filepath.Join(dir, filepath.Base(dest)))
+}
+
+// pruneVersions deletes all but the keepVersions most recent entries under
+// versionsDir.
+func pruneVersions(versionsDir, scheme string) error {
+	versions, err := sortedVersions(versionsDir, scheme)
+	if err != nil {
+		return err
+	}
+
+	if len(versions) <= keepVersions {
+		return nil
+	}
+
+	for _, v := range versions[:len(versions)-keepVersions] {
+		if err := os.RemoveAll(filepath.Join(versionsDir, v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedVersions lists the version directories under versionsDir, oldest
+// first, ordered per scheme (see version.Comparator) rather than lexically -
+// plain string sorting puts "2.10.0" before "2.9.0" under semver, the
+// default scheme for new releases.
+func sortedVersions(versionsDir, scheme string) ([]string, error) {
+	entries, err := os.ReadDir(versionsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, e := range entries {
+		if e.IsDir() {
+			versions = append(versions, e.Name())
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return (version.Version{Raw: versions[i], Scheme: scheme}).Before(version.Version{Raw: versions[j], Scheme: scheme})
+	})
+	return versions, nil
+}
+
+// installedVersionSidecar is where Install/Rollback record which version is
+// currently at dest, so the next Install knows what to archive it as.
+func installedVersionSidecar(dest string) string {
+	return dest + ".version"
+}
+
+func writeInstalledVersion(dest, version string) error {
+	return os.WriteFile(installedVersionSidecar(dest), []byte(version), 0644)
+}
+
+func readInstalledVersion(dest string) string {
+	data, err := os.ReadFile(installedVersionSidecar(dest))
+	if err != nil {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(data))
+}
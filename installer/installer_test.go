@@ -0,0 +1,247 @@
+package installer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sorayaormazabalmayo/TUF-Client/artifact"
+)
+
+// fakeFetcher serves data out of memory. By default it honors the resume
+// offset the way a real artifact.Fetcher would; ignoreOffset simulates one
+// that doesn't (always returns the full artifact from byte 0).
+type fakeFetcher struct {
+	data         []byte
+	delay        time.Duration
+	ignoreOffset bool
+
+	callCount  atomic.Int32
+	lastOffset atomic.Int64
+}
+
+func (f *fakeFetcher) Fetch(ctx context.Context, ref artifact.Ref, offset int64) (io.ReadCloser, int64, bool, error) {
+	f.callCount.Add(1)
+	f.lastOffset.Store(offset)
+	if f.delay > 0 {
+		time.Sleep(f.delay)
+	}
+	if f.ignoreOffset {
+		return io.NopCloser(bytes.NewReader(f.data)), int64(len(f.data)), false, nil
+	}
+	return io.NopCloser(bytes.NewReader(f.data[offset:])), int64(len(f.data)), true, nil
+}
+
+func hashOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestInstallFreshDownload(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "artifact")
+	data := []byte("hello world")
+
+	ti := TargetInfo{Version: "1.0.0", Length: int64(len(data)), SHA256: hashOf(data)}
+	if err := Install(context.Background(), ti, dest, &fakeFetcher{data: data}); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("reading installed artifact: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("installed artifact = %q, want %q", got, data)
+	}
+}
+
+func TestInstallRejectsHashMismatch(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "artifact")
+	data := []byte("hello world")
+
+	ti := TargetInfo{Version: "1.0.0", Length: int64(len(data)), SHA256: "deadbeef"}
+	if err := Install(context.Background(), ti, dest, &fakeFetcher{data: data}); err == nil {
+		t.Fatal("expected Install to fail on hash mismatch")
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("dest should not exist after a failed install, stat err = %v", err)
+	}
+	if _, err := os.Stat(dest + ".partial"); !os.IsNotExist(err) {
+		t.Fatalf("partial file should be cleaned up after a failed install, stat err = %v", err)
+	}
+}
+
+func TestInstallSerializesConcurrentCallers(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "artifact")
+	data := []byte("hello world")
+	ti := TargetInfo{Version: "1.0.0", Length: int64(len(data)), SHA256: hashOf(data)}
+	fetcher := &fakeFetcher{data: data, delay: 50 * time.Millisecond}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = Install(context.Background(), ti, dest, fetcher)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Install[%d] failed: %v", i, err)
+		}
+	}
+	if got := fetcher.callCount.Load(); got != 2 {
+		t.Fatalf("expected both installs to fetch once each, serialized by the lock, got %d Fetch calls", got)
+	}
+	if _, err := os.Stat(dest + ".lock"); !os.IsNotExist(err) {
+		t.Fatalf("lockfile should be removed once Install completes, stat err = %v", err)
+	}
+}
+
+func TestInstallResumesPartialDownload(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "artifact")
+	data := []byte("hello world, this is a longer artifact body")
+	prefix := data[:16]
+	if err := os.WriteFile(dest+".partial", prefix, 0644); err != nil {
+		t.Fatalf("seeding partial download: %v", err)
+	}
+
+	fetcher := &fakeFetcher{data: data}
+	ti := TargetInfo{Version: "1.0.0", Length: int64(len(data)), SHA256: hashOf(data)}
+	if err := Install(context.Background(), ti, dest, fetcher); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+
+	if got, want := fetcher.lastOffset.Load(), int64(len(prefix)); got != want {
+		t.Errorf("fetcher was called with offset %d, want %d (the resume point)", got, want)
+	}
+
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("reading installed artifact: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("installed artifact = %q, want %q", got, data)
+	}
+}
+
+func TestInstallFallsBackToFullDownloadWhenOffsetIgnored(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "artifact")
+	data := []byte("hello world, this is a longer artifact body")
+	prefix := data[:16]
+	if err := os.WriteFile(dest+".partial", prefix, 0644); err != nil {
+		t.Fatalf("seeding partial download: %v", err)
+	}
+
+	// This fetcher behaves like a server that ignores Range and returns
+	// the full artifact from byte 0 - Install must detect that from
+	// honoredOffset and restart clean rather than appending the full body
+	// onto the existing prefix.
+	fetcher := &fakeFetcher{data: data, ignoreOffset: true}
+	ti := TargetInfo{Version: "1.0.0", Length: int64(len(data)), SHA256: hashOf(data)}
+	if err := Install(context.Background(), ti, dest, fetcher); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("reading installed artifact: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("installed artifact = %q, want %q", got, data)
+	}
+}
+
+func TestAcquireLockStealsStaleLockfile(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "artifact")
+	lockPath := dest + ".lock"
+	if err := os.WriteFile(lockPath, nil, 0644); err != nil {
+		t.Fatalf("seeding stale lockfile: %v", err)
+	}
+	stale := time.Now().Add(-lockStaleAfter - time.Minute)
+	if err := os.Chtimes(lockPath, stale, stale); err != nil {
+		t.Fatalf("backdating lockfile mtime: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	release, err := acquireLock(ctx, dest)
+	if err != nil {
+		t.Fatalf("acquireLock should have stolen the stale lock instead of waiting on it forever: %v", err)
+	}
+	release()
+}
+
+func TestPruneVersionsOrdersBySemverNotLexically(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "artifact")
+
+	for _, v := range []string{"1.2.0", "1.9.0", "1.10.0", "1.11.0", "2.0.0"} {
+		data := []byte("artifact " + v)
+		ti := TargetInfo{Version: v, Scheme: "semver", Length: int64(len(data)), SHA256: hashOf(data)}
+		if err := Install(context.Background(), ti, dest, &fakeFetcher{data: data}); err != nil {
+			t.Fatalf("Install %s failed: %v", v, err)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, versionsDirName))
+	if err != nil {
+		t.Fatalf("reading versions dir: %v", err)
+	}
+	kept := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		kept[e.Name()] = true
+	}
+
+	// keepVersions (3) of the 4 archived predecessors should survive:
+	// everything except the oldest, "1.2.0". A lexical sort instead puts
+	// "1.10.0" and "1.11.0" before "1.9.0", so it would prune the wrong
+	// entry and keep "1.2.0" around.
+	if kept["1.2.0"] {
+		t.Errorf("oldest version 1.2.0 should have been pruned, versions/ = %v", entries)
+	}
+	for _, v := range []string{"1.9.0", "1.10.0", "1.11.0"} {
+		if !kept[v] {
+			t.Errorf("version %s should have been kept, versions/ = %v", v, entries)
+		}
+	}
+}
+
+func TestRollbackRestoresPreviousVersion(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "artifact")
+	v1, v2 := []byte("version one"), []byte("version two")
+
+	install := func(v string, data []byte) {
+		t.Helper()
+		ti := TargetInfo{Version: v, Scheme: "semver", Length: int64(len(data)), SHA256: hashOf(data)}
+		if err := Install(context.Background(), ti, dest, &fakeFetcher{data: data}); err != nil {
+			t.Fatalf("installing %s: %v", v, err)
+		}
+	}
+	install("1.0.0", v1)
+	install("2.0.0", v2)
+
+	if err := Rollback(dest, "semver"); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("reading rolled-back artifact: %v", err)
+	}
+	if !bytes.Equal(got, v1) {
+		t.Fatalf("after rollback, dest = %q, want %q (v1)", got, v1)
+	}
+}
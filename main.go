@@ -4,45 +4,60 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	stdlog "log"
-	"mime"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
 	"time"
 
 	"github.com/go-logr/stdr"
-	"golang.org/x/oauth2/google"
 
 	"github.com/theupdateframework/go-tuf/v2/metadata"
 	"github.com/theupdateframework/go-tuf/v2/metadata/config"
 	"github.com/theupdateframework/go-tuf/v2/metadata/updater"
+
+	"github.com/sorayaormazabalmayo/TUF-Client/artifact"
+	"github.com/sorayaormazabalmayo/TUF-Client/autoupdate"
+	"github.com/sorayaormazabalmayo/TUF-Client/index"
+	"github.com/sorayaormazabalmayo/TUF-Client/installer"
+	"github.com/sorayaormazabalmayo/TUF-Client/state"
+	"github.com/sorayaormazabalmayo/TUF-Client/version"
 )
 
 // The following config is used to fetch a target from Jussi's GitHub repository example
 const (
-	metadataURL          = "https://sorayaormazabalmayo.github.io/TUF_Repository_YubiKey_Vault/metadata"
-	targetsURL           = "https://sorayaormazabalmayo.github.io/TUF_Repository_YubiKey_Vault/targets"
-	verbosity            = 4
-	generateRandomFolder = false
-	nameOfFile           = "index.json"
+	metadataURL           = "https://sorayaormazabalmayo.github.io/TUF_Repository_YubiKey_Vault/metadata"
+	targetsURL            = "https://sorayaormazabalmayo.github.io/TUF_Repository_YubiKey_Vault/targets"
+	verbosity             = 4
+	generateRandomFolder  = false
+	nameOfFile            = "index.json"
+	installedArtifactName = "nebula-standalone"
 )
 
-type indexInfo struct {
-	Length int64 `json:"length"`
-	Hashes struct {
-		Sha256 string `json:"sha256"`
-	} `json:"hashes"`
-	Version string `json:"version"`
-}
+// serviceAccountKeyPathEnv is the environment variable -service-account-key
+// falls back to when unset, so the path to the Artifact Registry/GCS
+// credentials isn't hard-coded to any one machine's home directory.
+const serviceAccountKeyPathEnv = "ARTIFACT_DOWNLOADER_KEY_PATH"
 
 func main() {
 
-	// Define the desired layout
-	layout := "2006.01.02-15.04.05"
+	if len(os.Args) > 1 && os.Args[1] == "tufstatus" {
+		runTufStatus()
+		return
+	}
+
+	channel := flag.String("channel", index.StableChannel, "release channel to install from")
+	list := flag.Bool("list", false, "list installable artifacts for this platform and channel, then exit")
+	serviceAccountKey := flag.String("service-account-key", os.Getenv(serviceAccountKeyPathEnv),
+		"path to the service account key used to authenticate artifact downloads (defaults to $"+serviceAccountKeyPathEnv+")")
+	flag.Parse()
+
+	selector := index.Selector{OS: runtime.GOOS, Arch: runtime.GOARCH, Channel: *channel}
 
 	// This is the first step for setting the initial configuration.
 
@@ -65,7 +80,7 @@ func main() {
 	}
 
 	// Download the target index considering trusted targets role
-	targetIndexFile, foundDesiredTargetIndexLocally, err := DownloadTargetIndex(metadataDir)
+	targetIndexFile, foundDesiredTargetIndexLocally, up, err := DownloadTargetIndex(metadataDir)
 
 	if err != nil {
 		log.Error(err, "Download index file failed")
@@ -82,142 +97,131 @@ func main() {
 		fmt.Printf("\nThe local index file is the most updated one \n")
 	}
 
-	// Getting the latest version of the desired file
-
-	// Map to hold the top-level JSON keys
-	var data map[string]indexInfo
-
-	// Parse JSON into the map
-	err = json.Unmarshal([]byte(targetIndexFile), &data)
+	// Parse the index and select the entry for this platform and channel
+	idx, err := index.Parse(targetIndexFile)
 	if err != nil {
-		fmt.Printf("Error parsing JSON: %v", err)
+		log.Error(err, "Error parsing index")
 	}
-	// Latest version considering the index.json downloaded by TUF
-
-	indexVersion := data["nebula-standalone"].Version
-
-	//hashLatestVersion := data["nebula-standalone"].Hashes
 
-	// Service account key file
-	serviceAccountKeyPath := "/home/sormazabal/artifact-downloader-key.json"
-
-	// Construct Artifact Registry URL
-	url := fmt.Sprintf("https://artifactregistry.googleapis.com/download/v1/projects/polished-medium-445107-i9/locations/europe-southwest1/repositories/nebula-storage/files/nebula-package:%s:nebula-standalone:download?alt=media", indexVersion)
-
-	fmt.Printf("Downloading binary from: %s\n", url)
+	if *list {
+		for _, e := range selector.ListAvailable(idx) {
+			fmt.Printf("%s %s/%s/%s\t%s\n", e.Name, e.OS, e.Arch, e.EffectiveChannel(), e.Version)
+		}
+		return
+	}
 
-	// Download the artifact without specifying the file type
-	err = downloadArtifact(serviceAccountKeyPath, url)
+	entry, err := selector.Select(idx, installedArtifactName)
 	if err != nil {
-		fmt.Printf("Failed to download binary: %v\n", err)
+		log.Error(err, "Selecting installable entry failed")
 		os.Exit(1)
 	}
 
-	verficationAnswer := verifyingDownloadedFile(string(targetIndexFile), "tmp/downloaded-file")
-
-	if verficationAnswer == 1 {
-		fmt.Printf("\U0001F7E2Binary downloaded successfully!\U0001F7E2\n")
-	} else {
-		fmt.Printf("\U0001F534There has been an error while downloading the file. The hashed do not match\U0001F534\n")
-
+	if err := entry.Validate(up); err != nil {
+		log.Error(err, "Index entry did not match TUF targets metadata")
+		os.Exit(1)
 	}
 
-	currentVersion := data["nebula-standalone"].Version
+	// Service account key file
+	serviceAccountKeyPath := *serviceAccountKey
 
-	// Printing expiration date
-	PrintExpirationDate(layout, currentVersion)
+	currentVersion, err := ensureInstalled(context.Background(), metadataDir, serviceAccountKeyPath, entry)
+	if err != nil {
+		fmt.Printf("\U0001F534Failed to install binary: %v\U0001F534\n", err)
+		os.Exit(1)
+	}
 
-	fmt.Printf("\nThe current nebula-standalone version is: %s \n", currentVersion)
+	// Printing expiration date, computed from the signed targets metadata
+	printTargetsExpiration(metadataDir)
 
-	time.Sleep(time.Second * 60)
+	source := &tufSource{metadataDir: metadataDir, serviceAccountKeyPath: serviceAccountKeyPath, selector: selector}
 
-	// The updater needs to be looking for new updates every x time
-	for {
-		// download the desired target
-		targetIndexFile, foundDesiredTargetIndexLocally, err := DownloadTargetIndex(metadataDir)
+	autoUpdater := autoupdate.New(source, currentVersion, autoupdate.PromptUser)
+	autoUpdater.CheckInterval = 60 * time.Second
+	autoUpdater.Jitter = 10 * time.Second
+	autoUpdater.Confirm = func(newVersion string) bool {
+		fmt.Printf("There is a new product of nebula-standalone\n")
 
-		if err != nil {
-			log.Error(err, "Download index file failed")
+		if gettingUserAnswer() == 1 {
+			return true
 		}
 
-		if foundDesiredTargetIndexLocally == 0 {
-
-			err = os.WriteFile(filepath.Join(metadataDir, nameOfFile), targetIndexFile, 0750)
-			if err != nil {
-				log.Error(err, "Error writing to file")
-			}
-
-			// Verifying that the index.json's version is latest than the one that is currently running
-
-			// Map to hold the top-level JSON keys
-			var data map[string]indexInfo
-
-			// Parse JSON into the map
-			err = json.Unmarshal([]byte(targetIndexFile), &data)
-			if err != nil {
-				fmt.Printf("\U0001F534Error parsing JSON: %v\U0001F534", err)
-			}
-			// Latest version considering the index.json downloaded by TUF
-
-			indexVersion := data["nebula-standalone"].Version
-
-			newProductVersion := NewVersion(currentVersion, indexVersion, layout)
-
-			if newProductVersion == 1 {
-				fmt.Printf("There is a new product of nebula-standalone\n")
-			} else {
-				fmt.Printf("There is no new product\n")
-			}
-
-			// Getting user answer
-
-			userAnswer := gettingUserAnswer()
-
-			if userAnswer == 1 {
-
-				//hashLatestVersion := data["nebula-standalone"].Hashes
-
-				// Service account key file
-				serviceAccountKeyPath := "/home/sormazabal/artifact-downloader-key.json"
-
-				// Construct Artifact Registry URL
-				url := fmt.Sprintf("https://artifactregistry.googleapis.com/download/v1/projects/polished-medium-445107-i9/locations/europe-southwest1/repositories/nebula-storage/files/nebula-package:%s:nebula-standalone:download?alt=media", indexVersion)
-
-				fmt.Printf("Downloading binary from: %s\n", url)
-
-				// Download the artifact without specifying the file type
-				err = downloadArtifact(serviceAccountKeyPath, url)
-				if err != nil {
-					fmt.Printf("\U0001F534Failed to download binary: %v\U0001F534\n", err)
-					os.Exit(1)
-				}
-
-				verficationAnswer := verifyingDownloadedFile(string(targetIndexFile), "tmp/downloaded-file")
+		fmt.Printf("\u23F0Remember that you have an update pending.\u23F0\n")
+		printTargetsExpiration(metadataDir)
+		return false
+	}
 
-				if verficationAnswer == 1 {
-					fmt.Printf("\U0001F7E2Binary downloaded successfully!\U0001F7E2\n")
-				} else {
-					fmt.Printf("\U0001F534There has been an error while downloading the file. The hashed do not match\n\U0001F534")
+	for event := range autoUpdater.Run(context.Background()) {
+		switch event.Type {
+		case autoupdate.Installed:
+			currentVersion = event.Version
+			fmt.Printf("\U0001F7E2Binary downloaded successfully!\U0001F7E2\n")
+		case autoupdate.Failed:
+			log.Error(event.Err, "Update attempt failed")
+		}
+	}
+}
 
-				}
+// tufSource adapts this repo's TUF index + artifact backend install flow
+// to autoupdate.Source, so AutoUpdater can drive it without knowing
+// anything about TUF or GCP.
+type tufSource struct {
+	metadataDir           string
+	serviceAccountKeyPath string
+	selector              index.Selector
+
+	// lastEntry holds the entry selected by the most recent CheckVersion
+	// call, so the following Install call (for the same tick) doesn't
+	// need to re-download and re-select from index.json.
+	lastEntry index.Entry
+}
 
-			} else {
+// CheckVersion implements autoupdate.Source.
+func (s *tufSource) CheckVersion(ctx context.Context, currentVersion string) (string, bool, error) {
+	targetIndexFile, foundDesiredTargetIndexLocally, up, err := DownloadTargetIndex(s.metadataDir)
+	if err != nil {
+		return "", false, fmt.Errorf("download index file failed: %w", err)
+	}
 
-				fmt.Printf("\u23F0Remember that you have an update pending.\u23F0\n")
+	if foundDesiredTargetIndexLocally == 1 {
+		fmt.Printf("\nThe local index file is the most updated one\n")
+		return "", false, nil
+	}
 
-				// Telling the user the expiration date of the current version
+	if err := os.WriteFile(filepath.Join(s.metadataDir, nameOfFile), targetIndexFile, 0750); err != nil {
+		return "", false, fmt.Errorf("error writing to file: %w", err)
+	}
 
-				PrintExpirationDate(layout, currentVersion)
+	idx, err := index.Parse(targetIndexFile)
+	if err != nil {
+		return "", false, fmt.Errorf("error parsing index: %w", err)
+	}
 
-			}
+	entry, err := s.selector.Select(idx, installedArtifactName)
+	if err != nil {
+		return "", false, err
+	}
+	if err := entry.Validate(up); err != nil {
+		return "", false, fmt.Errorf("index entry did not match TUF targets metadata: %w", err)
+	}
+	s.lastEntry = entry
 
-		} else {
-			fmt.Printf("\nThe local index file is the most updated one\n")
-		}
+	current := version.Version{Raw: currentVersion, Scheme: entry.Scheme}
+	remote := version.Version{Raw: entry.Version, Scheme: entry.Scheme}
+	cmp, err := current.Compare(remote)
+	if err != nil {
+		return "", false, fmt.Errorf("comparing installed version %q to index version %q: %w", currentVersion, entry.Version, err)
+	}
 
-		time.Sleep(time.Second * 60)
+	return entry.Version, cmp < 0, nil
+}
 
+// Install implements autoupdate.Source.
+func (s *tufSource) Install(ctx context.Context, newVersion string) error {
+	ref, err := installArtifact(ctx, s.metadataDir, s.serviceAccountKeyPath, s.lastEntry)
+	if err != nil {
+		return err
 	}
+	return recordInstall(s.metadataDir, s.lastEntry, ref)
 }
 
 // InitEnvironment prepares the local environment - temporary folders, etc.
@@ -289,46 +293,61 @@ func InitTrustOnFirstUse(metadataDir string) error {
 
 // DownloadTargetIndex downloads the target file using Updater. The Updater refreshes the top-level metadata,
 // get the target information, verifies if the target is already cached, and in case it
-// is not cached, downloads the target file.
+// is not cached, downloads the target file. It also returns the refreshed
+// Updater itself, so callers can use it to look up TUF targets metadata
+// for the entries index.json names (see index.Entry.Validate).
 
-func DownloadTargetIndex(localMetadataDir string) ([]byte, int, error) {
-	// log := metadata.GetLogger()
+func DownloadTargetIndex(localMetadataDir string) ([]byte, int, *updater.Updater, error) {
+	log := metadata.GetLogger()
 
 	rootBytes, err := os.ReadFile(filepath.Join(localMetadataDir, "root.json"))
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
 
 	// create updater configuration
 	cfg, err := config.New(metadataURL, rootBytes) // default config
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
 	cfg.LocalMetadataDir = localMetadataDir
 	cfg.LocalTargetsDir = filepath.Join(localMetadataDir, "download")
 	cfg.RemoteTargetsURL = targetsURL
+	// Consistent snapshots: every download is hash-prefixed (index.json
+	// becomes <sha256>.index.json on the remote), so a locally cached
+	// index.json can never be mistaken for a different snapshot's - a
+	// version bump always means a different hash and therefore a cache
+	// miss, rather than foundDesiredTargetIndexLocally silently serving a
+	// stale file.
 	cfg.PrefixTargetsWithHash = true
 
 	// create a new Updater instance
 	up, err := updater.New(cfg)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to create Updater instance: %w", err)
+		return nil, 0, nil, fmt.Errorf("failed to create Updater instance: %w", err)
 	}
 
 	// try to build the top-level metadata
 	err = up.Refresh()
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to refresh trusted metadata: %w", err)
+		// Dump per-role metadata status alongside the error so failures
+		// like bad signatures, rollback, or expired metadata can be
+		// diagnosed without reproducing the refresh by hand.
+		status := statusFromUpdater(localMetadataDir, up, err)
+		if statusJSON, marshalErr := json.MarshalIndent(status, "", "  "); marshalErr == nil {
+			log.Error(err, "Failed to refresh trusted metadata", "status", string(statusJSON))
+		}
+		return nil, 0, nil, fmt.Errorf("failed to refresh trusted metadata: %w", err)
 	}
 
 	ti, err := up.GetTargetInfo(nameOfFile)
 	if err != nil {
-		return nil, 0, fmt.Errorf("getting info for target index \"%s\": %w", nameOfFile, err)
+		return nil, 0, nil, fmt.Errorf("getting info for target index \"%s\": %w", nameOfFile, err)
 	}
 
 	path, tb, err := up.FindCachedTarget(ti, filepath.Join(localMetadataDir, nameOfFile))
 	if err != nil {
-		return nil, 0, fmt.Errorf("getting target index cache: %w", err)
+		return nil, 0, nil, fmt.Errorf("getting target index cache: %w", err)
 	}
 
 	// fmt.Printf("\n%s\n", tb)
@@ -337,7 +356,7 @@ func DownloadTargetIndex(localMetadataDir string) ([]byte, int, error) {
 	if path != "" {
 		// Cached version found
 		fmt.Println("\U0001F34C CACHE HIT")
-		return tb, 1, nil
+		return tb, 1, up, nil
 	}
 
 	// fmt.Printf("\nThere is a new update:\n")
@@ -345,10 +364,10 @@ func DownloadTargetIndex(localMetadataDir string) ([]byte, int, error) {
 	// Download of target is needed
 	_, tb, err = up.DownloadTarget(ti, "", "")
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to download target index file %s - %w", nameOfFile, err)
+		return nil, 0, nil, fmt.Errorf("failed to download target index file %s - %w", nameOfFile, err)
 	}
 
-	return tb, 0, nil
+	return tb, 0, up, nil
 }
 
 func gettingUserAnswer() int {
@@ -368,166 +387,360 @@ func gettingUserAnswer() int {
 
 }
 
-// downloadArtifact dynamically determines the file name and downloads the artifact
-func downloadArtifact(keyFilePath, url string) error {
-	// Authenticate using the service account key
-	ctx := context.Background()
-	creds, err := google.CredentialsFromJSON(ctx, readFile(keyFilePath), "https://www.googleapis.com/auth/cloud-platform")
-	if err != nil {
-		return fmt.Errorf("failed to load service account credentials: %w", err)
+// artifactRegistryProject, artifactRegistryLocation, and
+// artifactRegistryRepository identify where the nebula-standalone
+// artifact lives when a target's custom metadata doesn't say otherwise
+// (see fetcherFor's BackendArtifactRegistry, "" case).
+const (
+	artifactRegistryProject    = "polished-medium-445107-i9"
+	artifactRegistryLocation   = "europe-southwest1"
+	artifactRegistryRepository = "nebula-storage"
+)
+
+// fetcherFor returns the artifact.Fetcher that serves ref.Backend. S3
+// needs a region-scoped client built per call since the region comes from
+// the target's own metadata; the others are backend-wide.
+func fetcherFor(ctx context.Context, ref artifact.Ref, creds artifact.CredentialProvider) (artifact.Fetcher, error) {
+	switch ref.Backend {
+	case artifact.BackendArtifactRegistry, "":
+		return &artifact.ArtifactRegistryFetcher{
+			Project:     artifactRegistryProject,
+			Location:    artifactRegistryLocation,
+			Repository:  artifactRegistryRepository,
+			Credentials: creds,
+		}, nil
+	case artifact.BackendGCS:
+		return &artifact.GCSFetcher{Credentials: creds}, nil
+	case artifact.BackendS3:
+		return artifact.NewS3Fetcher(ctx, ref.Region)
+	case artifact.BackendHTTP:
+		return &artifact.HTTPFetcher{}, nil
+	default:
+		return nil, fmt.Errorf("unknown artifact backend %q", ref.Backend)
 	}
+}
 
-	// Create HTTP client with the token
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+// resolveRef returns the artifact.Ref info should be fetched from: info.Ref
+// as populated from the target's TUF custom metadata, or - for entries
+// published before that field existed - the Artifact Registry setup this
+// client originally shipped with.
+func resolveRef(info index.Entry) artifact.Ref {
+	if info.Ref.Backend == "" && info.Ref.Object == "" {
+		return artifact.Ref{
+			Backend: artifact.BackendArtifactRegistry,
+			Object:  fmt.Sprintf("nebula-package:%s:nebula-standalone", info.Version),
+		}
 	}
+	return info.Ref
+}
+
+// installArtifact installs the nebula-standalone artifact described by
+// info into metadataDir using the installer package: staged download,
+// hash re-verification, and atomic rename, with the previous binary
+// preserved for rollback. It returns the Ref it fetched from, so the caller
+// can record where the now-installed artifact came from.
+func installArtifact(ctx context.Context, metadataDir, serviceAccountKeyPath string, info index.Entry) (artifact.Ref, error) {
+	ref := resolveRef(info)
 
-	// Add Authorization header with Bearer token
-	token, err := creds.TokenSource.Token()
+	creds := artifact.ServiceAccountCredentialProvider{KeyFilePath: serviceAccountKeyPath}
+
+	fetcher, err := fetcherFor(ctx, ref, creds)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve token: %w", err)
+		return ref, fmt.Errorf("failed to resolve artifact backend: %w", err)
 	}
-	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
 
-	// Perform the request
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to execute request: %w", err)
+	fmt.Printf("Downloading binary from backend %q, ref %+v\n", ref.Backend, ref)
+
+	ti := installer.TargetInfo{
+		Version: info.Version,
+		Scheme:  info.Scheme,
+		Ref:     ref,
+		Length:  info.Length,
+		SHA256:  info.Hashes.Sha256,
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download artifact, status code: %d", resp.StatusCode)
+	dest := filepath.Join(metadataDir, installedArtifactName)
+	return ref, installer.Install(ctx, ti, dest, fetcher)
+}
+
+// sourceURLFor formats ref as a human-readable locator for state.Record's
+// SourceURL, one scheme per backend.
+func sourceURLFor(ref artifact.Ref) string {
+	switch ref.Backend {
+	case artifact.BackendGCS:
+		return fmt.Sprintf("gs://%s/%s", ref.Bucket, ref.Object)
+	case artifact.BackendS3:
+		return fmt.Sprintf("s3://%s/%s", ref.Bucket, ref.Object)
+	case artifact.BackendHTTP:
+		return ref.Object
+	default: // artifact.BackendArtifactRegistry, or "" before resolveRef's fallback
+		return fmt.Sprintf("artifact-registry://%s/%s/%s/%s", artifactRegistryProject, artifactRegistryLocation, artifactRegistryRepository, ref.Object)
 	}
+}
+
+// recordInstall persists that entry is now installed from ref, so the next
+// startup's self-heal check (see ensureInstalled) knows what should be on
+// disk instead of re-deriving it from whatever index.json says at that
+// time.
+func recordInstall(metadataDir string, entry index.Entry, ref artifact.Ref) error {
+	return state.Save(metadataDir, state.Record{
+		Name:          entry.Name,
+		Version:       entry.Version,
+		SHA256:        entry.Hashes.Sha256,
+		InstalledAt:   time.Now(),
+		SourceURL:     sourceURLFor(ref),
+		TUFTargetPath: entry.EffectiveTargetPath(),
+	})
+}
+
+// ensureInstalled makes sure entry's artifact is present and intact at
+// metadataDir/installedArtifactName. It installs when nothing is recorded
+// as installed yet, when the on-disk binary no longer hashes to what was
+// recorded for the last install (tampering or corruption), when entry is
+// newer than the installed version, or when the hash check or the version
+// comparison itself fails; otherwise it leaves the existing install in
+// place. It returns the version now installed.
+func ensureInstalled(ctx context.Context, metadataDir, serviceAccountKeyPath string, entry index.Entry) (string, error) {
+	log := metadata.GetLogger()
 
-	// Determine the file name from the Content-Disposition header or use a default name
-	contentDisposition := resp.Header.Get("Content-Disposition")
-	fileName := "tmp/downloaded-file"
-	if contentDisposition != "" {
-		_, params, err := mime.ParseMediaType(contentDisposition)
-		if err == nil {
-			if name, ok := params["filename"]; ok {
-				fileName = name
+	records, err := state.Load(metadataDir)
+	if err != nil {
+		log.Error(err, "Failed to load installed-state manifest")
+		records = map[string]state.Record{}
+	}
+
+	installed, wasInstalled := records[entry.Name]
+	needsInstall := true
+
+	if wasInstalled {
+		dest := filepath.Join(metadataDir, installedArtifactName)
+		switch intact, err := verifyInstalledHash(dest, installed.SHA256); {
+		case err != nil:
+			log.Error(err, "Failed to verify installed binary against its recorded hash")
+		case !intact:
+			fmt.Printf("\U0001F534Installed binary does not match its recorded hash - re-downloading\U0001F534\n")
+		default:
+			cmp, err := (version.Version{Raw: installed.Version, Scheme: entry.Scheme}).Compare(version.Version{Raw: entry.Version, Scheme: entry.Scheme})
+			if err != nil {
+				log.Error(err, "Failed to compare installed version to index version")
+			} else if cmp >= 0 {
+				needsInstall = false
 			}
 		}
 	}
 
-	fmt.Printf("Saving file as: %s\n", fileName)
+	if !needsInstall {
+		fmt.Printf("\nThe installed nebula-standalone version is already up to date: %s \n", installed.Version)
+		return installed.Version, nil
+	}
 
-	// Write the response to a file
-	out, err := os.Create(fileName)
+	ref, err := installArtifact(ctx, metadataDir, serviceAccountKeyPath, entry)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return "", err
+	}
+	fmt.Printf("\U0001F7E2Binary downloaded successfully!\U0001F7E2\n")
+
+	if err := recordInstall(metadataDir, entry, ref); err != nil {
+		log.Error(err, "Failed to persist installed-state manifest")
 	}
-	defer out.Close()
 
-	_, err = io.Copy(out, resp.Body)
-	return err
+	fmt.Printf("\nThe current nebula-standalone version is: %s \n", entry.Version)
+	return entry.Version, nil
 }
 
-// readFile reads the content of the service account key JSON file
-func readFile(path string) []byte {
-	content, err := os.ReadFile(path)
+// verifyInstalledHash reports whether the file at path still hashes to
+// expectedSHA256. An empty expectedSHA256 (no prior install recorded it)
+// is treated as not intact, so the caller falls back to a fresh install.
+func verifyInstalledHash(path, expectedSHA256 string) (bool, error) {
+	if expectedSHA256 == "" {
+		return false, nil
+	}
+	sum, err := ComputeSHA256(path)
 	if err != nil {
-		fmt.Printf("\U0001F534Error reading file %s: %v\U0001F534\n", path, err)
-		os.Exit(1)
+		return false, err
 	}
-	return content
+	return sum == expectedSHA256, nil
 }
 
-func NewVersion(currentVersion, indexVersion, layout string) int {
-
-	var newVersion int
+// MetadataStatus describes the locally trusted state of a single TUF role
+// (one of root, timestamp, snapshot, targets, or a delegated targets role).
+// Error is set instead of the other fields when Refresh stopped before this
+// role's metadata could be loaded or verified.
+type MetadataStatus struct {
+	Version    int64
+	Size       int
+	Expiration time.Time
+	Error      string
+}
 
-	currentVersionParsed, err := time.Parse(layout, currentVersion)
+// RootStatus is a snapshot of the TUF client's trust state, meant to be
+// dumped as JSON for debugging update failures (bad signatures, rollback,
+// expired metadata) or inspected via the tufstatus subcommand.
+type RootStatus struct {
+	Local    string
+	Remote   string
+	Targets  []string
+	Metadata map[string]MetadataStatus
+}
 
+// Status builds a RootStatus for the TUF client rooted at metadataDir: it
+// refreshes the top-level metadata exactly as DownloadTargetIndex does and
+// reports how far that refresh got, so it can be called standalone (the
+// tufstatus subcommand) or on a refresh failure to aid debugging.
+func Status(metadataDir string) (RootStatus, error) {
+	rootBytes, err := os.ReadFile(filepath.Join(metadataDir, "root.json"))
 	if err != nil {
-		fmt.Printf("\U0001F534Error parsing version of the current version running: %v\U0001F534\n", err)
+		return RootStatus{Local: metadataDir, Remote: metadataURL}, err
 	}
 
-	indexVersionParsed, err := time.Parse(layout, indexVersion)
-
+	cfg, err := config.New(metadataURL, rootBytes)
 	if err != nil {
-		fmt.Printf("\U0001F534Error parsing the version that the index.json indicates: %v\U0001F534\n", err)
-	}
-
-	if currentVersionParsed.Before(indexVersionParsed) {
-		newVersion = 1
-	} else if currentVersionParsed.After(indexVersionParsed) {
-		newVersion = 0
-	} else {
-		newVersion = 0
+		return RootStatus{Local: metadataDir, Remote: metadataURL}, err
 	}
-	return newVersion
-}
-
-// Printing the expiratin date of a version
-
-func PrintExpirationDate(layout, currentVersion string) {
-
-	// Parse the string into a time.Time object
-	currentVersionParsed, err := time.Parse(layout, currentVersion)
+	cfg.LocalMetadataDir = metadataDir
+	cfg.LocalTargetsDir = filepath.Join(metadataDir, "download")
+	cfg.RemoteTargetsURL = targetsURL
+	cfg.PrefixTargetsWithHash = true // see the consistent-snapshot note in DownloadTargetIndex
 
+	up, err := updater.New(cfg)
 	if err != nil {
-		fmt.Printf("\U0001F534Error parsing the current version date: %v\U0001F534\n", err)
-		return
+		return RootStatus{Local: metadataDir, Remote: metadataURL}, fmt.Errorf("failed to create Updater instance: %w", err)
 	}
 
-	expirationDateOfCurrentVersion := currentVersionParsed.AddDate(2, 0, 0)
+	refreshErr := up.Refresh()
+	return statusFromUpdater(metadataDir, up, refreshErr), refreshErr
+}
 
-	currentDate := time.Now()
+// statusFromUpdater builds a RootStatus from an Updater that has already had
+// Refresh called on it (refreshErr is that call's result, nil or not), so
+// DownloadTargetIndex can report a failed refresh without refreshing twice.
+func statusFromUpdater(metadataDir string, up *updater.Updater, refreshErr error) RootStatus {
+	trusted := up.GetTrustedMetadataSet()
 
-	validTimeOfCurrentVersion := expirationDateOfCurrentVersion.Sub(currentDate)
+	status := RootStatus{
+		Local:    metadataDir,
+		Remote:   metadataURL,
+		Metadata: map[string]MetadataStatus{},
+	}
 
-	totalHours := int(validTimeOfCurrentVersion.Hours())
-	totalDays := totalHours / 24
-	years := totalDays / 365
-	days := totalDays % 365
-	hours := totalHours % 24
-	minutes := int(validTimeOfCurrentVersion.Minutes()) % 60
-	seconds := int(validTimeOfCurrentVersion.Seconds()) % 60
+	status.Metadata[metadata.ROOT] = MetadataStatus{
+		Version:    trusted.Root.Signed.Version,
+		Expiration: trusted.Root.Signed.Expires,
+		Size:       metadataFileSize(metadataDir, metadata.ROOT),
+	}
+	if trusted.Timestamp != nil {
+		status.Metadata[metadata.TIMESTAMP] = MetadataStatus{
+			Version:    trusted.Timestamp.Signed.Version,
+			Expiration: trusted.Timestamp.Signed.Expires,
+			Size:       metadataFileSize(metadataDir, metadata.TIMESTAMP),
+		}
+	}
+	if trusted.Snapshot != nil {
+		status.Metadata[metadata.SNAPSHOT] = MetadataStatus{
+			Version:    trusted.Snapshot.Signed.Version,
+			Expiration: trusted.Snapshot.Signed.Expires,
+			Size:       metadataFileSize(metadataDir, metadata.SNAPSHOT),
+		}
+	}
+	for role, targets := range trusted.Targets {
+		status.Metadata[role] = MetadataStatus{
+			Version:    targets.Signed.Version,
+			Expiration: targets.Signed.Expires,
+			Size:       metadataFileSize(metadataDir, role),
+		}
+		for name := range targets.Signed.Targets {
+			status.Targets = append(status.Targets, name)
+		}
+	}
 
-	fmt.Printf("\u23F0The current version will expire in %d years, %d days, %d hours, %d minutes, and %d seconds\u23F0\n",
-		years, days, hours, minutes, seconds)
+	if refreshErr != nil {
+		// Attribute the error to the first role in root -> timestamp ->
+		// snapshot -> targets order that Refresh didn't get to load, so
+		// it's obvious which stage broke.
+		failedRole := metadata.TARGETS
+		switch {
+		case trusted.Timestamp == nil:
+			failedRole = metadata.TIMESTAMP
+		case trusted.Snapshot == nil:
+			failedRole = metadata.SNAPSHOT
+		}
+		status.Metadata[failedRole] = MetadataStatus{Error: refreshErr.Error()}
+	}
 
+	return status
 }
 
-func verifyingDownloadedFile(indexPath, DonwloadedFilePath string) int {
-
-	// Hash of the index.json file
-	var data map[string]indexInfo
+// metadataFileSize returns the size in bytes of role's persisted local
+// metadata file, or 0 if it can't be stat'd (e.g. local caching disabled).
+func metadataFileSize(metadataDir, role string) int {
+	info, err := os.Stat(filepath.Join(metadataDir, role+".json"))
+	if err != nil {
+		return 0
+	}
+	return int(info.Size())
+}
 
-	// Parse JSON into the map
-	err := json.Unmarshal([]byte(indexPath), &data)
+// runTufStatus implements the tufstatus subcommand: it bootstraps trust the
+// same way main does, then prints the resulting RootStatus as JSON.
+func runTufStatus() {
+	metadataDir, err := InitEnvironment()
 	if err != nil {
-		fmt.Printf("\U0001F534Error parsing JSON: %v\U0001F534", err)
+		fmt.Printf("\U0001F534Failed to initialize environment: %v\U0001F534\n", err)
+		os.Exit(1)
 	}
-	// Latest version considering the index.json downloaded by TUF
 
-	indexHash := data["nebula-standalone"].Hashes.Sha256
+	if err := InitTrustOnFirstUse(metadataDir); err != nil {
+		fmt.Printf("\U0001F534Trust-On-First-Use failed: %v\U0001F534\n", err)
+		os.Exit(1)
+	}
 
-	// Computing the hash of the downloaded file
+	status, statusErr := Status(metadataDir)
 
-	// Compute the SHA256 hash
-	downloadedFilehash, err := ComputeSHA256(DonwloadedFilePath)
+	statusJSON, err := json.MarshalIndent(status, "", "  ")
 	if err != nil {
-		fmt.Printf("\U0001F534Error computing hash: %v\U0001F534\n", err)
-		return 0
+		fmt.Printf("\U0001F534Failed to marshal TUF status: %v\U0001F534\n", err)
+		os.Exit(1)
 	}
+	fmt.Println(string(statusJSON))
 
-	if indexHash == downloadedFilehash {
+	if statusErr != nil {
+		os.Exit(1)
+	}
+}
 
-		fmt.Printf("\U0001F7E2The target file has been downloaded successfully!\U0001F7E2\n")
-		return 1
-	} else {
-		fmt.Printf("\U0001F534There has been an error while downloading the file\U0001F534\n")
-		return 0
+// printTargetsExpiration prints how long the targets role's signed metadata
+// remains valid, computed from its own expires field rather than guessed
+// from a version string.
+func printTargetsExpiration(metadataDir string) {
+	status, err := Status(metadataDir)
+	if err != nil {
+		fmt.Printf("\U0001F534Error computing TUF status: %v\U0001F534\n", err)
+		return
 	}
 
+	targets, ok := status.Metadata[metadata.TARGETS]
+	if !ok {
+		fmt.Printf("\U0001F534No targets metadata available to compute expiration\U0001F534\n")
+		return
+	}
+
+	validTime := time.Until(targets.Expiration)
+
+	totalHours := int(validTime.Hours())
+	totalDays := totalHours / 24
+	years := totalDays / 365
+	days := totalDays % 365
+	hours := totalHours % 24
+	minutes := int(validTime.Minutes()) % 60
+	seconds := int(validTime.Seconds()) % 60
+
+	fmt.Printf("\u23F0The current targets metadata will expire in %d years, %d days, %d hours, %d minutes, and %d seconds\u23F0\n",
+		years, days, hours, minutes, seconds)
 }
 
+// ComputeSHA256 hashes the file at filePath. It is kept as a standalone
+// helper for callers that need to re-verify an already-installed artifact
+// (see the installer package for verification during installation itself).
 func ComputeSHA256(filePath string) (string, error) {
 	// Open the file
 	file, err := os.Open(filePath)
@@ -0,0 +1,64 @@
+// Package state persists which version of each artifact this client has
+// actually installed, so a restart can tell what's on disk instead of
+// re-deriving it from whatever index.json says at that moment (see
+// main.go's DownloadTargetIndex, which refreshes the index on every run).
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileName is the installed-state manifest's name within a client's
+// metadata directory.
+const fileName = "installed.json"
+
+// Record describes one artifact this client has installed.
+type Record struct {
+	Name          string    `json:"name"`
+	Version       string    `json:"version"`
+	SHA256        string    `json:"sha256"`
+	InstalledAt   time.Time `json:"installedAt"`
+	SourceURL     string    `json:"sourceURL"`
+	TUFTargetPath string    `json:"tufTargetPath"`
+}
+
+// Load reads the installed-state manifest from metadataDir, keyed by
+// artifact name. A metadataDir with no manifest yet (nothing has been
+// installed through this package) is reported as an empty, non-nil map
+// rather than an error.
+func Load(metadataDir string) (map[string]Record, error) {
+	data, err := os.ReadFile(filepath.Join(metadataDir, fileName))
+	if os.IsNotExist(err) {
+		return map[string]Record{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", fileName, err)
+	}
+
+	var records map[string]Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", fileName, err)
+	}
+	return records, nil
+}
+
+// Save merges rec into metadataDir's manifest, keyed by rec.Name, leaving
+// any other artifact's record untouched.
+func Save(metadataDir string, rec Record) error {
+	records, err := Load(metadataDir)
+	if err != nil {
+		return err
+	}
+	records[rec.Name] = rec
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", fileName, err)
+	}
+
+	return os.WriteFile(filepath.Join(metadataDir, fileName), data, 0644)
+}
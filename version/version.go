@@ -0,0 +1,215 @@
+// Package version orders the version strings index.json entries carry.
+// Semver (https://semver.org) is the default scheme for new releases; the
+// timestamp layout this client originally versioned releases with
+// ("2006.01.02-15.04.05") is kept available as a second Comparator so
+// already-published entries keep comparing correctly.
+package version
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scheme names a version ordering scheme. It is the value expected in an
+// index.json entry's "scheme" field.
+const (
+	SchemeSemver    = "semver"
+	SchemeTimestamp = "timestamp"
+)
+
+// DefaultTimestampLayout is the time.Parse layout this client's releases
+// were versioned with before semver became the default scheme.
+const DefaultTimestampLayout = "2006.01.02-15.04.05"
+
+// Comparator orders two raw version strings of the same scheme.
+type Comparator interface {
+	// Compare returns -1, 0, or 1 if a sorts before, the same as, or after
+	// b. It returns an error if either string isn't valid for the scheme.
+	Compare(a, b string) (int, error)
+}
+
+// Version is a single release's version string together with the scheme it
+// should be ordered by.
+type Version struct {
+	Raw string
+	// Scheme selects the Comparator Compare uses. Empty defaults to
+	// SchemeSemver, since that's this client's default scheme for new
+	// releases.
+	Scheme string
+}
+
+// Compare orders v against other using v's Scheme.
+func (v Version) Compare(other Version) (int, error) {
+	cmp, err := comparatorFor(v.Scheme).Compare(v.Raw, other.Raw)
+	if err != nil {
+		return 0, fmt.Errorf("comparing version %q to %q: %w", v.Raw, other.Raw, err)
+	}
+	return cmp, nil
+}
+
+// Before reports whether v sorts before other. It returns false, not an
+// error, if either version string fails to parse under v's Scheme - callers
+// that need to distinguish "not newer" from "unparseable" should use
+// Compare directly.
+func (v Version) Before(other Version) bool {
+	cmp, err := v.Compare(other)
+	return err == nil && cmp < 0
+}
+
+func comparatorFor(scheme string) Comparator {
+	if scheme == SchemeTimestamp {
+		return TimestampComparator{Layout: DefaultTimestampLayout}
+	}
+	return SemverComparator{}
+}
+
+// TimestampComparator orders version strings as timestamps in Layout,
+// preserving this client's original versioning scheme.
+type TimestampComparator struct {
+	Layout string
+}
+
+// Compare implements Comparator.
+func (c TimestampComparator) Compare(a, b string) (int, error) {
+	ta, err := time.Parse(c.Layout, a)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %q with layout %q: %w", a, c.Layout, err)
+	}
+	tb, err := time.Parse(c.Layout, b)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %q with layout %q: %w", b, c.Layout, err)
+	}
+	switch {
+	case ta.Before(tb):
+		return -1, nil
+	case ta.After(tb):
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// SemverComparator orders version strings per the precedence rules at
+// semver.org: numeric major.minor.patch first, then pre-release identifiers
+// compared left to right (numeric identifiers numerically, alphanumeric
+// ones lexically; a release with no pre-release outranks one with; a
+// pre-release that's a strict prefix of another sorts first). Build
+// metadata (a "+" suffix) is ignored entirely, per spec.
+type SemverComparator struct{}
+
+// Compare implements Comparator.
+func (SemverComparator) Compare(a, b string) (int, error) {
+	va, err := parseSemver(a)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %q as semver: %w", a, err)
+	}
+	vb, err := parseSemver(b)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %q as semver: %w", b, err)
+	}
+	return va.compare(vb), nil
+}
+
+type semver struct {
+	major, minor, patch int
+	// preRelease is nil for a release version, which outranks any
+	// pre-release of the same major.minor.patch.
+	preRelease []string
+}
+
+func parseSemver(s string) (semver, error) {
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i] // build metadata carries no precedence
+	}
+
+	core := s
+	var preRelease []string
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		core = s[:i]
+		preRelease = strings.Split(s[i+1:], ".")
+	}
+
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return semver{}, fmt.Errorf("expected major.minor.patch, got %q", core)
+	}
+	nums := make([]int, 3)
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return semver{}, fmt.Errorf("non-numeric version component %q", p)
+		}
+		nums[i] = n
+	}
+
+	return semver{major: nums[0], minor: nums[1], patch: nums[2], preRelease: preRelease}, nil
+}
+
+func (a semver) compare(b semver) int {
+	if d := sign(a.major - b.major); d != 0 {
+		return d
+	}
+	if d := sign(a.minor - b.minor); d != 0 {
+		return d
+	}
+	if d := sign(a.patch - b.patch); d != 0 {
+		return d
+	}
+	return comparePreRelease(a.preRelease, b.preRelease)
+}
+
+func comparePreRelease(a, b []string) int {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	if len(a) == 0 {
+		// a is a release, b is a pre-release: a outranks b.
+		return 1
+	}
+	if len(b) == 0 {
+		return -1
+	}
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareIdentifier(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return sign(len(a) - len(b))
+}
+
+func compareIdentifier(a, b string) int {
+	na, aIsNum := toInt(a)
+	nb, bIsNum := toInt(b)
+	switch {
+	case aIsNum && bIsNum:
+		return sign(na - nb)
+	case aIsNum:
+		return -1 // numeric identifiers always sort lower than alphanumeric ones
+	case bIsNum:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func toInt(s string) (int, bool) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
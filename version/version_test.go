@@ -0,0 +1,68 @@
+package version
+
+import "testing"
+
+func TestSemverComparatorPrecedence(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "2.0.0", -1},
+		{"2.0.0", "1.0.0", 1},
+		{"1.2.0", "1.10.0", -1}, // a lexical sort would get this backwards
+		{"1.0.0", "1.0.0", 0},
+		{"1.0.0-alpha", "1.0.0", -1},              // pre-release outranked by release
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},      // prefix sorts first
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1}, // numeric identifiers sort before alphanumeric
+		{"1.0.0-beta", "1.0.0-alpha", 1},
+		{"1.0.0+build1", "1.0.0+build2", 0}, // build metadata carries no precedence
+		{"v1.2.3", "1.2.3", 0},              // leading "v" is tolerated
+	}
+
+	cmp := SemverComparator{}
+	for _, c := range cases {
+		got, err := cmp.Compare(c.a, c.b)
+		if err != nil {
+			t.Fatalf("Compare(%q, %q) returned error: %v", c.a, c.b, err)
+		}
+		if got != c.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestSemverComparatorRejectsInvalid(t *testing.T) {
+	cmp := SemverComparator{}
+	if _, err := cmp.Compare("not-a-version", "1.0.0"); err == nil {
+		t.Fatal("expected an error for a non-semver string")
+	}
+}
+
+func TestTimestampComparator(t *testing.T) {
+	cmp := TimestampComparator{Layout: DefaultTimestampLayout}
+
+	got, err := cmp.Compare("2024.01.02-03.04.05", "2024.06.07-08.09.10")
+	if err != nil {
+		t.Fatalf("Compare returned error: %v", err)
+	}
+	if got != -1 {
+		t.Errorf("Compare = %d, want -1", got)
+	}
+}
+
+func TestVersionCompareUsesScheme(t *testing.T) {
+	a := Version{Raw: "2024.01.02-03.04.05", Scheme: SchemeTimestamp}
+	b := Version{Raw: "2024.06.07-08.09.10", Scheme: SchemeTimestamp}
+
+	if !a.Before(b) {
+		t.Fatal("expected a to sort before b under the timestamp scheme")
+	}
+}
+
+func TestVersionBeforeIsFalseOnParseError(t *testing.T) {
+	a := Version{Raw: "not-a-version"}
+	b := Version{Raw: "1.0.0"}
+	if a.Before(b) {
+		t.Fatal("Before should report false, not panic or error, for an unparseable version")
+	}
+}